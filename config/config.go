@@ -0,0 +1,118 @@
+// Package config parses and validates the exporter's YAML configuration
+// file, which describes the outlets to scrape in more detail than the
+// legacy -outlets flag (per-outlet auth, scheme, timeout and extra labels).
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Outlet describes a single Tasmota device to scrape, and the connection
+// settings needed to reach it.
+type Outlet struct {
+	Name     string            `yaml:"name"`
+	Address  string            `yaml:"address"`
+	Username string            `yaml:"username,omitempty"`
+	Password string            `yaml:"password,omitempty"`
+	Scheme   string            `yaml:"scheme,omitempty"`
+	Timeout  Duration          `yaml:"timeout,omitempty"`
+	Labels   map[string]string `yaml:"labels,omitempty"`
+	// Topic is the outlet's Tasmota MQTT topic (its configured %topic%).
+	// When set, the outlet is fed from MQTT telemetry instead of being
+	// polled over HTTP at Address.
+	Topic string `yaml:"topic,omitempty"`
+}
+
+// Duration is a time.Duration that unmarshals from a YAML string like "5s"
+// or "500ms", the way -mqtt-stale-after already accepts via flag.Duration,
+// instead of yaml.v3's default of decoding a bare scalar as raw nanoseconds.
+type Duration time.Duration
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Config is the top-level structure of the exporter's YAML config file.
+type Config struct {
+	Outlets []Outlet `yaml:"outlets"`
+}
+
+// Load reads and validates the config file at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// reservedLabelNames are the label names the collector adds to every metric
+// itself (main.go's buildOutletLabels/relayLabelNames); an outlet can't also
+// declare one of these via Labels without producing a GaugeVec with a
+// duplicate label name.
+var reservedLabelNames = map[string]bool{
+	"outlet": true,
+	"relay":  true,
+}
+
+// Validate checks the config for missing fields and obviously wrong values.
+func (c *Config) Validate() error {
+	seen := make(map[string]bool, len(c.Outlets))
+	for _, o := range c.Outlets {
+		if o.Name == "" {
+			return fmt.Errorf("outlet: name is required")
+		}
+		if seen[o.Name] {
+			return fmt.Errorf("outlet %q: duplicate name", o.Name)
+		}
+		seen[o.Name] = true
+
+		// A pure MQTT-fed outlet (Topic set) never gets HTTP-polled, so it
+		// doesn't need an address to be reachable at; firewalled devices that
+		// only push telemetry can be declared without one. The control API
+		// still needs an address to issue Power commands, but that's the
+		// control API's problem to surface, not config's to require up front.
+		if o.Address == "" && o.Topic == "" {
+			return fmt.Errorf("outlet %q: address or topic is required", o.Name)
+		}
+
+		switch o.Scheme {
+		case "", "http", "https":
+		default:
+			return fmt.Errorf("outlet %q: scheme must be http or https, got %q", o.Name, o.Scheme)
+		}
+
+		for label := range o.Labels {
+			if reservedLabelNames[label] {
+				return fmt.Errorf("outlet %q: label %q is reserved", o.Name, label)
+			}
+		}
+	}
+
+	return nil
+}
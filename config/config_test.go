@@ -0,0 +1,149 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+	return path
+}
+
+func TestLoad_ParsesDurationStrings(t *testing.T) {
+	path := writeConfig(t, `
+outlets:
+  - name: livingroom
+    address: 192.168.1.100
+    timeout: 5s
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	if got, want := time.Duration(cfg.Outlets[0].Timeout), 5*time.Second; got != want {
+		t.Errorf("Timeout = %v, want %v", got, want)
+	}
+}
+
+func TestLoad_InvalidDuration(t *testing.T) {
+	path := writeConfig(t, `
+outlets:
+  - name: livingroom
+    address: 192.168.1.100
+    timeout: not-a-duration
+`)
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("Load() expected an error for an invalid timeout, got nil")
+	}
+}
+
+func TestLoad_Topic(t *testing.T) {
+	path := writeConfig(t, `
+outlets:
+  - name: bedroom
+    address: 192.168.1.101
+    topic: bedroom-plug
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	if got, want := cfg.Outlets[0].Topic, "bedroom-plug"; got != want {
+		t.Errorf("Topic = %q, want %q", got, want)
+	}
+}
+
+func TestLoad_TopicWithoutAddress(t *testing.T) {
+	// A firewalled, MQTT-fed outlet never gets HTTP-polled, so it has no
+	// address to declare.
+	path := writeConfig(t, `
+outlets:
+  - name: bedroom
+    topic: bedroom-plug
+`)
+
+	if _, err := Load(path); err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			cfg: Config{Outlets: []Outlet{
+				{Name: "livingroom", Address: "192.168.1.100"},
+			}},
+		},
+		{
+			name:    "missing name",
+			cfg:     Config{Outlets: []Outlet{{Address: "192.168.1.100"}}},
+			wantErr: true,
+		},
+		{
+			name:    "missing address and topic",
+			cfg:     Config{Outlets: []Outlet{{Name: "livingroom"}}},
+			wantErr: true,
+		},
+		{
+			name: "topic without address",
+			cfg: Config{Outlets: []Outlet{
+				{Name: "bedroom", Topic: "bedroom-plug"},
+			}},
+		},
+		{
+			name: "duplicate name",
+			cfg: Config{Outlets: []Outlet{
+				{Name: "livingroom", Address: "192.168.1.100"},
+				{Name: "livingroom", Address: "192.168.1.101"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "invalid scheme",
+			cfg: Config{Outlets: []Outlet{
+				{Name: "livingroom", Address: "192.168.1.100", Scheme: "ftp"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "reserved label outlet",
+			cfg: Config{Outlets: []Outlet{
+				{Name: "livingroom", Address: "192.168.1.100", Labels: map[string]string{"outlet": "x"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "reserved label relay",
+			cfg: Config{Outlets: []Outlet{
+				{Name: "livingroom", Address: "192.168.1.100", Labels: map[string]string{"relay": "x"}},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
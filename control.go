@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// controlAPI implements the outlet control endpoints: GET /outlets lists the
+// configured outlets and their last observed state, and POST
+// /outlets/{name}/power issues a Power command against one of them. Both
+// require a bearer token, since they let a caller toggle physical hardware.
+type controlAPI struct {
+	ref   *collectorRef
+	token string
+}
+
+// newControlAPI returns a controlAPI that authenticates requests against
+// token.
+func newControlAPI(ref *collectorRef, token string) *controlAPI {
+	return &controlAPI{ref: ref, token: token}
+}
+
+// authenticate checks the request's bearer token, writing a 401 and
+// returning false if it doesn't match. The comparison is constant-time since
+// this endpoint gates physical relay control.
+func (a *controlAPI) authenticate(w http.ResponseWriter, r *http.Request) bool {
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if got == "" || subtle.ConstantTimeCompare([]byte(got), []byte(a.token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// outletSummary is the JSON shape returned for each outlet by listOutlets.
+type outletSummary struct {
+	Name string `json:"name"`
+	Up   bool   `json:"up"`
+	On   bool   `json:"on"`
+}
+
+// listOutlets handles GET /outlets, returning the configured outlets and
+// their most recently observed reachability and power state, without
+// re-probing the devices.
+func (a *controlAPI) listOutlets(w http.ResponseWriter, r *http.Request) {
+	if !a.authenticate(w, r) {
+		return
+	}
+
+	c := a.ref.Get()
+	summaries := make([]outletSummary, 0, len(c.outlets))
+	for _, outlet := range c.outlets {
+		summary := outletSummary{Name: outlet.Name}
+		if state, ok := c.State(outlet.Name); ok {
+			summary.Up = state.up
+			if state.status != nil {
+				if relays := state.status.StatusSTS.Relays(); len(relays) > 0 {
+					summary.On = relays[0]
+				}
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summaries); err != nil {
+		slog.Error("failed to encode outlet list", "error", err)
+	}
+}
+
+// powerRequest is the body expected by setOutletPower.
+type powerRequest struct {
+	State string `json:"state"`
+}
+
+// powerResult is the body returned by setOutletPower.
+type powerResult struct {
+	Name string `json:"name"`
+	On   bool   `json:"on"`
+}
+
+// setOutletPower handles POST /outlets/{name}/power: it issues a Tasmota
+// Power command (ON, OFF, or TOGGLE, selected by the "state" field) against
+// the named outlet and returns the resulting relay state.
+func (a *controlAPI) setOutletPower(w http.ResponseWriter, r *http.Request) {
+	if !a.authenticate(w, r) {
+		return
+	}
+
+	name := r.PathValue("name")
+
+	c := a.ref.Get()
+	var outlet Outlet
+	var found bool
+	for _, o := range c.outlets {
+		if o.Name == name {
+			outlet, found = o, true
+			break
+		}
+	}
+	if !found {
+		http.Error(w, fmt.Sprintf("outlet %q not found", name), http.StatusNotFound)
+		return
+	}
+
+	var req powerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var cmnd string
+	switch strings.ToLower(req.State) {
+	case "on":
+		cmnd = "ON"
+	case "off":
+		cmnd = "OFF"
+	case "toggle":
+		cmnd = "TOGGLE"
+	default:
+		http.Error(w, fmt.Sprintf("invalid state %q: must be on, off, or toggle", req.State), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	on, err := c.setPower(ctx, outlet, cmnd)
+	observePowerCommand(name, cmnd, err)
+	if err != nil {
+		slog.Warn("power command failed", "outlet", name, "command", cmnd, "error", err)
+		http.Error(w, fmt.Sprintf("power command failed: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	slog.Info("power command succeeded", "outlet", name, "command", cmnd, "on", on)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(powerResult{Name: name, On: on}); err != nil {
+		slog.Error("failed to encode power command result", "error", err)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestControlAPI(t *testing.T, outlets []Outlet) (*controlAPI, *collectorRef) {
+	t.Helper()
+	ref := &collectorRef{}
+	ref.Set(NewTasmotaCollector(outlets))
+	return newControlAPI(ref, "test-token"), ref
+}
+
+func TestControlAPI_Authenticate(t *testing.T) {
+	api, _ := newTestControlAPI(t, nil)
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{name: "missing header"},
+		{name: "wrong token", header: "Bearer wrong-token"},
+		{name: "empty bearer token", header: "Bearer "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/outlets", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			if ok := api.authenticate(w, req); ok {
+				t.Fatal("authenticate() = true, want false")
+			}
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/outlets", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+	if ok := api.authenticate(w, req); !ok {
+		t.Fatal("authenticate() = false, want true for the correct token")
+	}
+}
+
+func TestControlAPI_ListOutlets(t *testing.T) {
+	outlets := []Outlet{{Name: "livingroom", IP: "192.168.1.100"}}
+	api, ref := newTestControlAPI(t, outlets)
+
+	status := &TasmotaStatus{StatusSTS: StatusSTS{POWER: "ON"}}
+	ref.Get().recordState("livingroom", true, status)
+
+	req := httptest.NewRequest(http.MethodGet, "/outlets", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	api.listOutlets(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var got []outletSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	want := []outletSummary{{Name: "livingroom", Up: true, On: true}}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Errorf("listOutlets() = %+v, want %+v", got, want)
+	}
+}
+
+func TestControlAPI_SetOutletPower_UnknownOutlet(t *testing.T) {
+	api, _ := newTestControlAPI(t, []Outlet{{Name: "livingroom", IP: "192.168.1.100"}})
+
+	req := httptest.NewRequest(http.MethodPost, "/outlets/bedroom/power", strings.NewReader(`{"state":"on"}`))
+	req.SetPathValue("name", "bedroom")
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	api.setOutletPower(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestControlAPI_SetOutletPower_BadState(t *testing.T) {
+	api, _ := newTestControlAPI(t, []Outlet{{Name: "livingroom", IP: "192.168.1.100"}})
+
+	req := httptest.NewRequest(http.MethodPost, "/outlets/livingroom/power", strings.NewReader(`{"state":"sideways"}`))
+	req.SetPathValue("name", "livingroom")
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	api.setOutletPower(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestControlAPI_SetOutletPower_Success(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cmnd") != "Power ON" {
+			t.Errorf("unexpected command: %s", r.URL.Query().Get("cmnd"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"POWER":"ON"}`))
+	}))
+	defer mockServer.Close()
+
+	api, _ := newTestControlAPI(t, []Outlet{{Name: "livingroom", IP: mockServer.Listener.Addr().String()}})
+
+	req := httptest.NewRequest(http.MethodPost, "/outlets/livingroom/power", strings.NewReader(`{"state":"on"}`))
+	req.SetPathValue("name", "livingroom")
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	api.setOutletPower(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var got powerResult
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	want := powerResult{Name: "livingroom", On: true}
+	if got != want {
+		t.Errorf("setOutletPower() = %+v, want %+v", got, want)
+	}
+}
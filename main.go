@@ -9,11 +9,18 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/lstoll/tasmota-exporter/config"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/term"
@@ -23,12 +30,73 @@ import (
 type Outlet struct {
 	Name string
 	IP   string
+
+	// Topic is the outlet's Tasmota MQTT topic (its configured %topic%). When
+	// set, the collector reports metrics from MQTT telemetry it has received
+	// for this topic instead of polling IP over HTTP.
+	Topic string
+
+	// Username and Password are Tasmota's web-auth credentials, sent as
+	// &user=&password= query params. Only set when loaded from -config.file.
+	Username string
+	Password string
+	// Scheme is "http" or "https"; defaults to "http".
+	Scheme string
+	// Timeout overrides the default per-probe timeout when positive.
+	Timeout time.Duration
+	// Labels are extra Prometheus labels applied to this outlet's metrics.
+	Labels map[string]string
+}
+
+// defaultStaleAfter is how long an MQTT-fed outlet can go without a received
+// sample before tasmota_up reports it as down.
+const defaultStaleAfter = 5 * time.Minute
+
+// mqttReading is the most recently decoded telemetry payload for an
+// MQTT-fed outlet, along with when it was received.
+type mqttReading struct {
+	status TasmotaStatus
+	// stsReceived is true once a stat/RESULT message has populated
+	// status.StatusSTS. tele/SENSOR messages never carry relay state, so
+	// until a command is issued (and RESULT fires), status.StatusSTS is the
+	// type's zero value indistinguishable from a genuine single-relay "off" —
+	// emitReading needs this to know whether to trust it.
+	stsReceived bool
+	lastSeen    time.Time
 }
 
 // TasmotaCollector implements the prometheus.Collector interface
 type TasmotaCollector struct {
 	outlets []Outlet
 
+	// extraLabelNames is the sorted union of all outlets' Labels keys. Every
+	// gauge is created with "outlet" plus these names so a single outlet's
+	// config (e.g. a rack or room label) doesn't force a separate metric
+	// family per outlet.
+	extraLabelNames []string
+	// outletLabelValues maps an outlet name to its extra label values, in
+	// extraLabelNames order, with "" for labels it doesn't set.
+	outletLabelValues map[string][]string
+
+	staleAfter time.Duration
+
+	mqttMu       sync.Mutex
+	mqttReadings map[string]mqttReading
+
+	// energyMu guards energyBaselines, which lets energyTotal re-baseline
+	// tasmota_energy_kwh_total across a device-side counter reset so the
+	// series it reports keeps increasing instead of dropping to zero. Keyed
+	// by outlet name and relay, since each relay on a multi-relay device
+	// tracks its own cumulative total and can reset independently.
+	energyMu        sync.Mutex
+	energyBaselines map[string]*energyBaseline
+
+	// stateMu guards lastState, which lets the control API's GET /outlets
+	// report each outlet's most recently observed reachability and power
+	// state without re-probing the device.
+	stateMu   sync.Mutex
+	lastState map[string]outletState
+
 	// Metrics
 	onGauge            *prometheus.GaugeVec
 	voltageGauge       *prometheus.GaugeVec
@@ -39,64 +107,211 @@ type TasmotaCollector struct {
 	factorGauge        *prometheus.GaugeVec
 	todayGauge         *prometheus.GaugeVec
 	yesterdayGauge     *prometheus.GaugeVec
-	totalGauge         *prometheus.GaugeVec
 	upGauge            *prometheus.GaugeVec
 	temperatureGauge   *prometheus.GaugeVec
+
+	// energyTotalDesc describes tasmota_energy_kwh_total, emitted as a
+	// prometheus.CounterValue via energyTotal rather than a GaugeVec, so
+	// rate()/increase() behave correctly in PromQL.
+	energyTotalDesc *prometheus.Desc
+}
+
+// energyBaseline tracks what's needed to re-baseline a device's cumulative
+// energy counter across a device-side reset (detected via TotalStartTime
+// changing): offset is added to every subsequent Total so the reported
+// series keeps climbing instead of dropping back to zero.
+type energyBaseline struct {
+	startTime string
+	offset    float64
+	lastValue float64
+}
+
+// outletState is the most recently observed reachability and telemetry for
+// an outlet, recorded by Collect so the control API can report current
+// state without re-probing the device.
+type outletState struct {
+	up       bool
+	status   *TasmotaStatus
+	lastSeen time.Time
 }
 
 // NewTasmotaCollector creates a new collector for the given outlets
 func NewTasmotaCollector(outlets []Outlet) *TasmotaCollector {
+	extraLabelNames, outletLabelValues := buildOutletLabels(outlets)
+	labelNames := append([]string{"outlet"}, extraLabelNames...)
+	// relayLabelNames is used by the per-reading gauges, which report one
+	// series per relay on multi-relay devices; tasmota_up and the
+	// temperature gauge stay device-level and use labelNames instead.
+	relayLabelNames := append([]string{"outlet", "relay"}, extraLabelNames...)
+
 	return &TasmotaCollector{
-		outlets: outlets,
+		outlets:           outlets,
+		extraLabelNames:   extraLabelNames,
+		outletLabelValues: outletLabelValues,
+		staleAfter:        defaultStaleAfter,
+		mqttReadings:      make(map[string]mqttReading),
+		energyBaselines:   make(map[string]*energyBaseline),
+		lastState:         make(map[string]outletState),
 		onGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "tasmota_on",
 			Help: "Indicates if the tasmota plug is on/off",
-		}, []string{"outlet"}),
+		}, relayLabelNames),
 		voltageGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "tasmota_voltage_volts",
 			Help: "voltage of tasmota plug in volt (V)",
-		}, []string{"outlet"}),
+		}, relayLabelNames),
 		currentGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "tasmota_current_amperes",
 			Help: "current of tasmota plug in ampere (A)",
-		}, []string{"outlet"}),
+		}, relayLabelNames),
 		powerGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "tasmota_power_watts",
 			Help: "current power of tasmota plug in watts (W)",
-		}, []string{"outlet"}),
+		}, relayLabelNames),
 		apparentPowerGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "tasmota_apparent_power_voltamperes",
 			Help: "apparent power of tasmota plug in volt-amperes (VA)",
-		}, []string{"outlet"}),
+		}, relayLabelNames),
 		reactivePowerGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "tasmota_reactive_power_voltamperesreactive",
 			Help: "reactive power of tasmota plug in volt-amperes reactive (VAr)",
-		}, []string{"outlet"}),
+		}, relayLabelNames),
 		factorGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "tasmota_power_factor",
 			Help: "power factor of tasmota plug",
-		}, []string{"outlet"}),
+		}, relayLabelNames),
 		todayGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "tasmota_today_kwh_total",
 			Help: "todays energy usage total in kilowatts hours (kWh)",
-		}, []string{"outlet"}),
+		}, relayLabelNames),
 		yesterdayGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "tasmota_yesterday_kwh_total",
 			Help: "yesterdays energy usage total in kilowatts hours (kWh)",
-		}, []string{"outlet"}),
-		totalGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "tasmota_kwh_total",
-			Help: "total energy usage in kilowatts hours (kWh)",
-		}, []string{"outlet"}),
+		}, relayLabelNames),
 		upGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "tasmota_up",
 			Help: "Indicates if the tasmota outlet is reachable",
-		}, []string{"outlet"}),
+		}, labelNames),
 		temperatureGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "tasmota_temperature_celsius",
 			Help: "temperature of the ESP32 chip in celsius",
-		}, []string{"outlet"}),
+		}, labelNames),
+		energyTotalDesc: prometheus.NewDesc(
+			"tasmota_energy_kwh_total",
+			"cumulative energy usage in kilowatt hours (kWh), monotonically increasing across device-side counter resets",
+			relayLabelNames, nil,
+		),
+	}
+}
+
+// energyTotal returns the cumulative kWh value to report for outlet/relay,
+// re-baselined against any previous device-side counter reset detected via
+// a change in TotalStartTime.
+func (c *TasmotaCollector) energyTotal(outlet, relay string, total float64, startTime string) float64 {
+	c.energyMu.Lock()
+	defer c.energyMu.Unlock()
+
+	key := outlet + "/" + relay
+	baseline, ok := c.energyBaselines[key]
+	if !ok {
+		baseline = &energyBaseline{startTime: startTime}
+		c.energyBaselines[key] = baseline
+	} else if startTime != "" && startTime != baseline.startTime {
+		baseline.offset += baseline.lastValue
+		baseline.startTime = startTime
+	}
+
+	baseline.lastValue = total
+
+	return baseline.offset + total
+}
+
+// buildOutletLabels computes the sorted union of extra label names declared
+// across outlets, and each outlet's values in that order (empty string for
+// names it doesn't set), so every outlet's metrics share one label set.
+func buildOutletLabels(outlets []Outlet) ([]string, map[string][]string) {
+	nameSet := make(map[string]struct{})
+	for _, o := range outlets {
+		for k := range o.Labels {
+			nameSet[k] = struct{}{}
+		}
+	}
+
+	names := make([]string, 0, len(nameSet))
+	for k := range nameSet {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	values := make(map[string][]string, len(outlets))
+	for _, o := range outlets {
+		v := make([]string, len(names))
+		for i, n := range names {
+			v[i] = o.Labels[n]
+		}
+		values[o.Name] = v
+	}
+
+	return names, values
+}
+
+// labelValues returns the full WithLabelValues argument list for outletName:
+// its name followed by its extra label values in extraLabelNames order.
+func (c *TasmotaCollector) labelValues(outletName string) []string {
+	return append([]string{outletName}, c.outletLabelValues[outletName]...)
+}
+
+// relayLabelValues returns the full WithLabelValues argument list for a
+// single relay of outletName: its name, the relay, then its extra label
+// values in extraLabelNames order.
+func (c *TasmotaCollector) relayLabelValues(outletName, relay string) []string {
+	return append([]string{outletName, relay}, c.outletLabelValues[outletName]...)
+}
+
+// SetStaleAfter overrides the default window used to decide whether an
+// MQTT-fed outlet's tasmota_up metric should report healthy.
+func (c *TasmotaCollector) SetStaleAfter(d time.Duration) {
+	c.staleAfter = d
+}
+
+// updateFromMQTT records a telemetry payload received over MQTT for the
+// given topic, for later use by Collect. sns and sts are merged onto
+// whatever was previously recorded for topic, since tele/SENSOR and
+// stat/RESULT arrive as separate messages carrying different sections of the
+// same device's status; pass nil for the section a given message doesn't
+// carry.
+func (c *TasmotaCollector) updateFromMQTT(topic string, sns *StatusSNS, sts *StatusSTS) {
+	c.mqttMu.Lock()
+	defer c.mqttMu.Unlock()
+
+	reading := c.mqttReadings[topic]
+	if sns != nil {
+		reading.status.StatusSNS = *sns
 	}
+	if sts != nil {
+		reading.status.StatusSTS = *sts
+		reading.stsReceived = true
+	}
+	reading.lastSeen = time.Now()
+	c.mqttReadings[topic] = reading
+}
+
+// recordState stores the most recently observed reachability and telemetry
+// for outletName, for the control API's GET /outlets to report.
+func (c *TasmotaCollector) recordState(outletName string, up bool, status *TasmotaStatus) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	c.lastState[outletName] = outletState{up: up, status: status, lastSeen: time.Now()}
+}
+
+// State returns the most recently observed reachability and telemetry for
+// outletName, if Collect has run at least once since the collector was
+// created.
+func (c *TasmotaCollector) State(outletName string) (outletState, bool) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	s, ok := c.lastState[outletName]
+	return s, ok
 }
 
 // Describe implements prometheus.Collector
@@ -110,9 +325,9 @@ func (c *TasmotaCollector) Describe(ch chan<- *prometheus.Desc) {
 	c.factorGauge.Describe(ch)
 	c.todayGauge.Describe(ch)
 	c.yesterdayGauge.Describe(ch)
-	c.totalGauge.Describe(ch)
 	c.upGauge.Describe(ch)
 	c.temperatureGauge.Describe(ch)
+	ch <- c.energyTotalDesc
 }
 
 // Collect implements prometheus.Collector
@@ -122,92 +337,186 @@ func (c *TasmotaCollector) Collect(ch chan<- prometheus.Metric) {
 	// Collect metrics for each outlet in parallel
 	for _, outlet := range c.outlets {
 		wg.Add(1)
+
+		if outlet.Topic != "" {
+			go func(outlet Outlet) {
+				defer wg.Done()
+				c.collectFromMQTT(outlet, ch)
+			}(outlet)
+			continue
+		}
+
 		go func(outlet Outlet) {
 			defer wg.Done()
 
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			timeout := outlet.Timeout
+			if timeout <= 0 {
+				timeout = 5 * time.Second
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
 			defer cancel()
 
-			status, err := c.probeTasmota(ctx, outlet.IP)
+			start := time.Now()
+			status, err := c.probeTasmota(ctx, outlet)
+			observeScrape(outlet.Name, start, err)
 
 			if err != nil {
 				slog.Warn("outlet probe failed", "outlet", outlet.Name, "ip", outlet.IP, "error", err)
 				// Only send up=0 metric for failed outlets, omit all other metrics
-				upMetric := c.upGauge.WithLabelValues(outlet.Name)
+				upMetric := c.upGauge.WithLabelValues(c.labelValues(outlet.Name)...)
 				upMetric.Set(0)
 				upMetric.Collect(ch)
+				c.recordState(outlet.Name, false, nil)
 				return
 			}
 
 			slog.Info("outlet probe successful", "outlet", outlet.Name, "ip", outlet.IP)
 
-			// Send up metric immediately
-			upMetric := c.upGauge.WithLabelValues(outlet.Name)
+			upMetric := c.upGauge.WithLabelValues(c.labelValues(outlet.Name)...)
 			upMetric.Set(1)
 			upMetric.Collect(ch)
 
-			// Send all other metrics for this outlet
-			if status.StatusSNS.ENERGY.Power > 0 {
-				onMetric := c.onGauge.WithLabelValues(outlet.Name)
-				onMetric.Set(1)
-				onMetric.Collect(ch)
-			} else {
-				onMetric := c.onGauge.WithLabelValues(outlet.Name)
-				onMetric.Set(0)
-				onMetric.Collect(ch)
+			// "status 0" always returns StatusSTS alongside StatusSNS, so the
+			// relay state it reports is always trustworthy.
+			c.emitReading(outlet.Name, status, true, ch)
+			c.recordState(outlet.Name, true, status)
+		}(outlet)
+	}
+
+	// Wait for all goroutines to complete
+	wg.Wait()
+}
+
+// collectFromMQTT emits tasmota_up plus, if a sample has been received
+// within the staleness window, the rest of the gauges for an MQTT-fed
+// outlet, using the most recent telemetry payload received for its topic.
+func (c *TasmotaCollector) collectFromMQTT(outlet Outlet, ch chan<- prometheus.Metric) {
+	c.mqttMu.Lock()
+	reading, ok := c.mqttReadings[outlet.Topic]
+	c.mqttMu.Unlock()
+
+	if !ok || time.Since(reading.lastSeen) > c.staleAfter {
+		slog.Warn("mqtt outlet stale or never seen", "outlet", outlet.Name, "topic", outlet.Topic)
+		upMetric := c.upGauge.WithLabelValues(c.labelValues(outlet.Name)...)
+		upMetric.Set(0)
+		upMetric.Collect(ch)
+		c.recordState(outlet.Name, false, nil)
+		return
+	}
+
+	upMetric := c.upGauge.WithLabelValues(c.labelValues(outlet.Name)...)
+	upMetric.Set(1)
+	upMetric.Collect(ch)
+
+	c.emitReading(outlet.Name, &reading.status, reading.stsReceived, ch)
+	c.recordState(outlet.Name, true, &reading.status)
+}
+
+// emitReading sends the ENERGY/ESP32 gauges for a single outlet, derived
+// from status, onto ch. It is shared by the HTTP polling and MQTT ingestion
+// paths so both keep the same metrics in sync.
+//
+// stsKnown says whether status.StatusSTS actually reflects a relay-state
+// reading rather than being the type's unset zero value: HTTP polling's
+// "status 0" always returns it, but an MQTT-fed outlet only gets one from an
+// explicit stat/RESULT (fired on a Power command), so its relay state falls
+// back to the power-based heuristic until one arrives.
+//
+// Per-reading gauges are emitted once per relay, labeled "1".."N" in channel
+// order. Single-relay devices (the common case) report just one relay, so
+// existing single-relay dashboards keep working against relay="1".
+func (c *TasmotaCollector) emitReading(name string, status *TasmotaStatus, stsKnown bool, ch chan<- prometheus.Metric) {
+	energy := status.StatusSNS.ENERGY
+	relayStates := status.StatusSTS.Relays()
+
+	relayCount := len(relayStates)
+	if len(energy.Power) > relayCount {
+		relayCount = len(energy.Power)
+	}
+	if relayCount == 0 {
+		relayCount = 1
+	}
+
+	for i := 0; i < relayCount; i++ {
+		relay := strconv.Itoa(i + 1)
+		labels := c.relayLabelValues(name, relay)
+
+		on := 0.0
+		switch {
+		case stsKnown && i < len(relayStates):
+			if relayStates[i] {
+				on = 1
 			}
+		case energy.Power.at(i) > 0:
+			on = 1
+		}
+		onMetric := c.onGauge.WithLabelValues(labels...)
+		onMetric.Set(on)
+		onMetric.Collect(ch)
 
-			voltageMetric := c.voltageGauge.WithLabelValues(outlet.Name)
-			voltageMetric.Set(status.StatusSNS.ENERGY.Voltage)
-			voltageMetric.Collect(ch)
+		voltageMetric := c.voltageGauge.WithLabelValues(labels...)
+		voltageMetric.Set(energy.Voltage.at(i))
+		voltageMetric.Collect(ch)
 
-			currentMetric := c.currentGauge.WithLabelValues(outlet.Name)
-			currentMetric.Set(status.StatusSNS.ENERGY.Current)
-			currentMetric.Collect(ch)
+		currentMetric := c.currentGauge.WithLabelValues(labels...)
+		currentMetric.Set(energy.Current.at(i))
+		currentMetric.Collect(ch)
 
-			powerMetric := c.powerGauge.WithLabelValues(outlet.Name)
-			powerMetric.Set(status.StatusSNS.ENERGY.Power)
-			powerMetric.Collect(ch)
+		powerMetric := c.powerGauge.WithLabelValues(labels...)
+		powerMetric.Set(energy.Power.at(i))
+		powerMetric.Collect(ch)
 
-			apparentPowerMetric := c.apparentPowerGauge.WithLabelValues(outlet.Name)
-			apparentPowerMetric.Set(status.StatusSNS.ENERGY.ApparentPower)
-			apparentPowerMetric.Collect(ch)
+		apparentPowerMetric := c.apparentPowerGauge.WithLabelValues(labels...)
+		apparentPowerMetric.Set(energy.ApparentPower.at(i))
+		apparentPowerMetric.Collect(ch)
 
-			reactivePowerMetric := c.reactivePowerGauge.WithLabelValues(outlet.Name)
-			reactivePowerMetric.Set(status.StatusSNS.ENERGY.ReactivePower)
-			reactivePowerMetric.Collect(ch)
+		reactivePowerMetric := c.reactivePowerGauge.WithLabelValues(labels...)
+		reactivePowerMetric.Set(energy.ReactivePower.at(i))
+		reactivePowerMetric.Collect(ch)
 
-			factorMetric := c.factorGauge.WithLabelValues(outlet.Name)
-			factorMetric.Set(status.StatusSNS.ENERGY.Factor)
-			factorMetric.Collect(ch)
+		factorMetric := c.factorGauge.WithLabelValues(labels...)
+		factorMetric.Set(energy.Factor.at(i))
+		factorMetric.Collect(ch)
 
-			todayMetric := c.todayGauge.WithLabelValues(outlet.Name)
-			todayMetric.Set(status.StatusSNS.ENERGY.Today)
-			todayMetric.Collect(ch)
+		todayMetric := c.todayGauge.WithLabelValues(labels...)
+		todayMetric.Set(energy.Today.at(i))
+		todayMetric.Collect(ch)
 
-			yesterdayMetric := c.yesterdayGauge.WithLabelValues(outlet.Name)
-			yesterdayMetric.Set(status.StatusSNS.ENERGY.Yesterday)
-			yesterdayMetric.Collect(ch)
+		yesterdayMetric := c.yesterdayGauge.WithLabelValues(labels...)
+		yesterdayMetric.Set(energy.Yesterday.at(i))
+		yesterdayMetric.Collect(ch)
 
-			totalMetric := c.totalGauge.WithLabelValues(outlet.Name)
-			totalMetric.Set(status.StatusSNS.ENERGY.Total)
-			totalMetric.Collect(ch)
+		total := c.energyTotal(name, relay, energy.Total.at(i), energy.TotalStartTime)
+		ch <- prometheus.MustNewConstMetric(c.energyTotalDesc, prometheus.CounterValue, total, labels...)
+	}
 
-			temperatureMetric := c.temperatureGauge.WithLabelValues(outlet.Name)
-			temperatureMetric.Set(status.StatusSNS.ESP32.Temperature)
-			temperatureMetric.Collect(ch)
-		}(outlet)
+	temperatureMetric := c.temperatureGauge.WithLabelValues(c.labelValues(name)...)
+	temperatureMetric.Set(status.StatusSNS.ESP32.Temperature)
+	temperatureMetric.Collect(ch)
+}
+
+// commandURL builds the Tasmota HTTP API URL that issues cmnd against
+// outlet, including web-auth credentials if configured.
+func commandURL(outlet Outlet, cmnd string) string {
+	scheme := outlet.Scheme
+	if scheme == "" {
+		scheme = "http"
 	}
 
-	// Wait for all goroutines to complete
-	wg.Wait()
+	reqURL := fmt.Sprintf("%s://%s/cm?cmnd=%s", scheme, outlet.IP, url.QueryEscape(cmnd))
+	if outlet.Username != "" {
+		reqURL += fmt.Sprintf("&user=%s&password=%s", url.QueryEscape(outlet.Username), url.QueryEscape(outlet.Password))
+	}
+	return reqURL
 }
 
-func (c *TasmotaCollector) probeTasmota(ctx context.Context, target string) (*TasmotaStatus, error) {
-	// Use the JSON API endpoint
-	url := fmt.Sprintf("http://%s/cm?cmnd=status%%2010", target)
+func (c *TasmotaCollector) probeTasmota(ctx context.Context, outlet Outlet) (*TasmotaStatus, error) {
+	target := outlet.IP
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	// "status 0" aggregates every status section (StatusSNS and StatusSTS,
+	// among others) into one response, which is what lets us read both
+	// per-relay energy and POWER1..N from one probe.
+	req, err := http.NewRequestWithContext(ctx, "GET", commandURL(outlet, "status 0"), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request for %s: %w", target, err)
 	}
@@ -235,11 +544,51 @@ func (c *TasmotaCollector) probeTasmota(ctx context.Context, target string) (*Ta
 	return &status, nil
 }
 
+// setPower issues a Tasmota Power command (ON, OFF, or TOGGLE) against
+// outlet's first relay and returns the resulting relay state as reported by
+// the device.
+func (c *TasmotaCollector) setPower(ctx context.Context, outlet Outlet, cmnd string) (bool, error) {
+	target := outlet.IP
+
+	req, err := http.NewRequestWithContext(ctx, "GET", commandURL(outlet, "Power "+cmnd), nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request for %s: %w", target, err)
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send power command to %s: %w", target, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to read power command response from %s: %w", target, err)
+	}
+
+	// Multi-relay devices answer with POWER1 rather than POWER; Relays reads
+	// either.
+	var result StatusSTS
+	if err := json.Unmarshal(body, &result); err != nil {
+		return false, fmt.Errorf("failed to parse power command response from %s: %w", target, err)
+	}
+
+	return result.Relays()[0], nil
+}
+
 func main() {
 	var (
-		listenAddr = flag.String("listen-addr", ":8092", "address to listen on")
-		outlets    = flag.String("outlets", "", "comma-separated list of outlet configurations in format 'name:ip' (e.g., 'livingroom:192.168.1.100,bedroom:192.168.1.101')")
-		logLevel   = flag.String("log-level", "info", "log level (debug, info, warn, error)")
+		listenAddr      = flag.String("listen-addr", ":8092", "address to listen on")
+		outlets         = flag.String("outlets", "", "comma-separated list of outlet configurations in format 'name:ip[:mqtt-topic]' (e.g., 'livingroom:192.168.1.100,bedroom:192.168.1.101:bedroom-plug'); ignored if -config.file is set")
+		configFile      = flag.String("config.file", "", "path to a YAML config file describing outlets (auth, scheme, timeout, extra labels); overrides -outlets and is reloaded on SIGHUP")
+		logLevel        = flag.String("log-level", "info", "log level (debug, info, warn, error)")
+		mqttBroker      = flag.String("mqtt-broker", "", "MQTT broker URI to subscribe for telemetry (e.g. 'tcp://localhost:1883'); outlets with a topic are fed from MQTT instead of being polled over HTTP")
+		mqttTopicPrefix = flag.String("mqtt-topic-prefix", "", "prefix Tasmota's tele/stat topics are published under, without a trailing slash")
+		mqttUsername    = flag.String("mqtt-username", "", "username for the MQTT broker")
+		mqttPassword    = flag.String("mqtt-password", "", "password for the MQTT broker")
+		mqttStaleAfter  = flag.Duration("mqtt-stale-after", defaultStaleAfter, "how long an MQTT-fed outlet can go without a sample before tasmota_up reports it as down")
+		controlToken    = flag.String("control-api-token", "", "bearer token required to call the outlet control API (GET /outlets, POST /outlets/{name}/power); leave unset to disable it")
 	)
 	flag.Parse()
 
@@ -276,25 +625,56 @@ func main() {
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
 
-	if *outlets == "" {
-		slog.Error("--outlets flag is required")
+	if *configFile == "" && *outlets == "" {
+		slog.Error("one of --config.file or --outlets is required")
 		os.Exit(1)
 	}
 
-	outletList := parseOutlets(*outlets)
-	if len(outletList) == 0 {
-		slog.Error("no valid outlet configurations found")
+	// ref holds the active collector so the MQTT ingester and /probe handler
+	// keep working across a SIGHUP config reload, which swaps in a fresh one.
+	ref := &collectorRef{}
+	if err := reloadCollector(ref, *configFile, *outlets, *mqttStaleAfter); err != nil {
+		slog.Error("failed to load outlet configuration", "error", err)
 		os.Exit(1)
 	}
 
-	slog.Info("configured outlets", "outlets", outletList)
+	if *mqttBroker != "" {
+		ingester, err := NewMQTTIngester(MQTTConfig{
+			Broker:      *mqttBroker,
+			TopicPrefix: *mqttTopicPrefix,
+			Username:    *mqttUsername,
+			Password:    *mqttPassword,
+		}, ref)
+		if err != nil {
+			slog.Error("failed to start mqtt ingester", "error", err)
+			os.Exit(1)
+		}
+		defer ingester.Close()
+	}
 
-	// Create and register collector
-	collector := NewTasmotaCollector(outletList)
-	prometheus.MustRegister(collector)
+	if *configFile != "" {
+		sighup := make(chan os.Signal, 1)
+		signal.Notify(sighup, syscall.SIGHUP)
+		go func() {
+			for range sighup {
+				slog.Info("reloading config", "file", *configFile)
+				if err := reloadCollector(ref, *configFile, *outlets, *mqttStaleAfter); err != nil {
+					slog.Error("failed to reload config, keeping previous configuration", "error", err)
+				}
+			}
+		}()
+	}
 
-	// Set up metrics endpoint
-	http.Handle("/metrics", promhttp.Handler())
+	// Set up metrics endpoints
+	http.Handle("/metrics", promhttp.HandlerFor(dataRegistry, promhttp.HandlerOpts{}))
+	http.Handle("/telemetry", promhttp.HandlerFor(telemetryRegistry, promhttp.HandlerOpts{}))
+	http.Handle("/probe", probeHandler(ref))
+
+	if *controlToken != "" {
+		api := newControlAPI(ref, *controlToken)
+		http.Handle("GET /outlets", http.HandlerFunc(api.listOutlets))
+		http.Handle("POST /outlets/{name}/power", http.HandlerFunc(api.setOutletPower))
+	}
 
 	slog.Info("starting tasmota exporter", "listen_addr", *listenAddr)
 	err := http.ListenAndServe(*listenAddr, nil)
@@ -316,8 +696,8 @@ func parseOutlets(outletsStr string) []Outlet {
 		}
 
 		parts := strings.Split(outletStr, ":")
-		if len(parts) != 2 {
-			slog.Warn("invalid outlet configuration", "config", outletStr, "expected_format", "name:ip")
+		if len(parts) != 2 && len(parts) != 3 {
+			slog.Warn("invalid outlet configuration", "config", outletStr, "expected_format", "name:ip[:mqtt-topic]")
 			continue
 		}
 
@@ -329,15 +709,105 @@ func parseOutlets(outletsStr string) []Outlet {
 			continue
 		}
 
-		outlets = append(outlets, Outlet{Name: name, IP: ip})
+		outlet := Outlet{Name: name, IP: ip}
+		if len(parts) == 3 {
+			outlet.Topic = strings.TrimSpace(parts[2])
+		}
+
+		outlets = append(outlets, outlet)
 	}
 
 	return outlets
 }
 
-// TasmotaStatus represents the JSON response from Tasmota status command
+// collectorRef holds the currently active TasmotaCollector so long-lived
+// consumers (the MQTT ingester, the /probe handler) keep working across a
+// SIGHUP config reload, which swaps in a freshly built collector.
+type collectorRef struct {
+	v atomic.Pointer[TasmotaCollector]
+}
+
+func (r *collectorRef) Get() *TasmotaCollector  { return r.v.Load() }
+func (r *collectorRef) Set(c *TasmotaCollector) { r.v.Store(c) }
+
+// loadOutlets builds the outlet list from configFile if set, falling back to
+// the legacy comma-separated outletsFlag otherwise.
+func loadOutlets(configFile, outletsFlag string) ([]Outlet, error) {
+	if configFile != "" {
+		cfg, err := config.Load(configFile)
+		if err != nil {
+			return nil, err
+		}
+		return outletsFromConfig(cfg), nil
+	}
+
+	outletList := parseOutlets(outletsFlag)
+	if len(outletList) == 0 {
+		return nil, fmt.Errorf("no valid outlet configurations found")
+	}
+	return outletList, nil
+}
+
+// outletsFromConfig converts a parsed YAML config into the Outlets the
+// collector works with.
+func outletsFromConfig(cfg *config.Config) []Outlet {
+	outlets := make([]Outlet, 0, len(cfg.Outlets))
+	for _, o := range cfg.Outlets {
+		outlets = append(outlets, Outlet{
+			Name:     o.Name,
+			IP:       o.Address,
+			Topic:    o.Topic,
+			Username: o.Username,
+			Password: o.Password,
+			Scheme:   o.Scheme,
+			Timeout:  time.Duration(o.Timeout),
+			Labels:   o.Labels,
+		})
+	}
+	return outlets
+}
+
+// reloadCollector builds a fresh TasmotaCollector from configFile/outletsFlag
+// and registers it in place of whatever ref currently holds, so config
+// changes (and a SIGHUP) take effect without restarting the process. On
+// error the previous collector, if any, is left registered and in ref.
+func reloadCollector(ref *collectorRef, configFile, outletsFlag string, staleAfter time.Duration) error {
+	outletList, err := loadOutlets(configFile, outletsFlag)
+	if err != nil {
+		return err
+	}
+
+	collector := NewTasmotaCollector(outletList)
+	collector.SetStaleAfter(staleAfter)
+
+	// The new collector describes the same metrics as the previous one, which
+	// the registry rejects while the previous one is still registered, so the
+	// previous one is unregistered first and registered again if the new one
+	// fails, leaving /metrics serving the old outlet set instead of nothing.
+	// The registry keeps each metric's label names fixed for the life of the
+	// process, so a reload that changes the set of extra label names fails.
+	prev := ref.Get()
+	if prev != nil {
+		dataRegistry.Unregister(prev)
+	}
+	if err := dataRegistry.Register(collector); err != nil {
+		if prev != nil {
+			_ = dataRegistry.Register(prev)
+		}
+		return fmt.Errorf("failed to register collector: %w", err)
+	}
+	ref.Set(collector)
+
+	slog.Info("configured outlets", "outlets", outletList)
+	return nil
+}
+
+// TasmotaStatus represents the JSON response from Tasmota's "status 0"
+// command, which aggregates every status section (including StatusSNS and
+// StatusSTS) into one payload.
 type TasmotaStatus struct {
 	StatusSNS StatusSNS `json:"StatusSNS"`
+	StatusSTS StatusSTS `json:"StatusSTS"`
 }
 
 // StatusSNS contains the sensor data
@@ -347,18 +817,88 @@ type StatusSNS struct {
 	ESP32  ESP32  `json:"ESP32"`
 }
 
-// Energy contains the power monitoring data
+// StatusSTS contains the live relay state. Multi-relay devices (Sonoff 4CH,
+// Shellies flashed with Tasmota, etc) report POWER1..POWER8 instead of the
+// single-relay POWER.
+type StatusSTS struct {
+	POWER  string `json:"POWER,omitempty"`
+	POWER1 string `json:"POWER1,omitempty"`
+	POWER2 string `json:"POWER2,omitempty"`
+	POWER3 string `json:"POWER3,omitempty"`
+	POWER4 string `json:"POWER4,omitempty"`
+	POWER5 string `json:"POWER5,omitempty"`
+	POWER6 string `json:"POWER6,omitempty"`
+	POWER7 string `json:"POWER7,omitempty"`
+	POWER8 string `json:"POWER8,omitempty"`
+}
+
+// Relays returns each relay's on/off state in channel order. Devices that
+// only report POWER are treated as a single relay, so single-relay dashboards
+// keep working against a relay="1" series.
+func (s StatusSTS) Relays() []bool {
+	numbered := []string{s.POWER1, s.POWER2, s.POWER3, s.POWER4, s.POWER5, s.POWER6, s.POWER7, s.POWER8}
+
+	var relays []bool
+	for _, p := range numbered {
+		if p == "" {
+			continue
+		}
+		relays = append(relays, strings.EqualFold(p, "ON"))
+	}
+	if len(relays) == 0 {
+		relays = []bool{strings.EqualFold(s.POWER, "ON")}
+	}
+	return relays
+}
+
+// Energy contains the power monitoring data. Single-relay devices report
+// each field as a scalar; multi-relay devices with per-channel energy
+// monitoring report them as an array, one entry per relay. flexFloats
+// decodes either shape.
 type Energy struct {
-	TotalStartTime string  `json:"TotalStartTime"`
-	Total          float64 `json:"Total"`
-	Yesterday      float64 `json:"Yesterday"`
-	Today          float64 `json:"Today"`
-	Power          float64 `json:"Power"`
-	ApparentPower  float64 `json:"ApparentPower"`
-	ReactivePower  float64 `json:"ReactivePower"`
-	Factor         float64 `json:"Factor"`
-	Voltage        float64 `json:"Voltage"`
-	Current        float64 `json:"Current"`
+	TotalStartTime string     `json:"TotalStartTime"`
+	Total          flexFloats `json:"Total"`
+	Yesterday      flexFloats `json:"Yesterday"`
+	Today          flexFloats `json:"Today"`
+	Power          flexFloats `json:"Power"`
+	ApparentPower  flexFloats `json:"ApparentPower"`
+	ReactivePower  flexFloats `json:"ReactivePower"`
+	Factor         flexFloats `json:"Factor"`
+	Voltage        flexFloats `json:"Voltage"`
+	Current        flexFloats `json:"Current"`
+}
+
+// flexFloats decodes a Tasmota ENERGY field that's a single number on
+// single-relay devices but an array on multi-relay ones with per-channel
+// monitoring.
+type flexFloats []float64
+
+func (f *flexFloats) UnmarshalJSON(data []byte) error {
+	var single float64
+	if err := json.Unmarshal(data, &single); err == nil {
+		*f = flexFloats{single}
+		return nil
+	}
+
+	var multi []float64
+	if err := json.Unmarshal(data, &multi); err != nil {
+		return err
+	}
+	*f = multi
+	return nil
+}
+
+// at returns the value for relay index i, falling back to the sole value for
+// fields shared across relays (e.g. bus voltage on a multi-relay device).
+func (f flexFloats) at(i int) float64 {
+	switch {
+	case len(f) == 0:
+		return 0
+	case i < len(f):
+		return f[i]
+	default:
+		return f[0]
+	}
 }
 
 // ESP32 contains ESP32-specific data like temperature
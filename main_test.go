@@ -13,7 +13,7 @@ import (
 func TestTasmotaCollector_Smoke(t *testing.T) {
 	// Create a mock Tasmota server
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/cm" || r.URL.Query().Get("cmnd") != "status 10" {
+		if r.URL.Path != "/cm" || r.URL.Query().Get("cmnd") != "status 0" {
 			t.Errorf("unexpected request: %s %s", r.Method, r.URL.String())
 			http.Error(w, "not found", http.StatusNotFound)
 			return
@@ -25,20 +25,21 @@ func TestTasmotaCollector_Smoke(t *testing.T) {
 				Time: "2025-01-15T10:30:00",
 				ENERGY: Energy{
 					TotalStartTime: "2025-01-15T00:00:00",
-					Total:          1.5,
-					Yesterday:      0.8,
-					Today:          0.7,
-					Power:          45.2,
-					ApparentPower:  50.0,
-					ReactivePower:  20.0,
-					Factor:         0.9,
-					Voltage:        240.0,
-					Current:        0.2,
+					Total:          flexFloats{1.5},
+					Yesterday:      flexFloats{0.8},
+					Today:          flexFloats{0.7},
+					Power:          flexFloats{45.2},
+					ApparentPower:  flexFloats{50.0},
+					ReactivePower:  flexFloats{20.0},
+					Factor:         flexFloats{0.9},
+					Voltage:        flexFloats{240.0},
+					Current:        flexFloats{0.2},
 				},
 				ESP32: ESP32{
 					Temperature: 42.5,
 				},
 			},
+			StatusSTS: StatusSTS{POWER: "ON"},
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -56,18 +57,18 @@ func TestTasmotaCollector_Smoke(t *testing.T) {
 	// Create collector
 	collector := NewTasmotaCollector(outlets)
 
-	// Test Describe - should have 12 metric descriptions (all our gauges)
+	// Test Describe - should have 12 metric descriptions (our gauges plus the energy counter)
 	expectedDescs := 12
 	descCount := testutil.CollectAndCount(collector, "tasmota_up", "tasmota_on", "tasmota_voltage_volts",
 		"tasmota_current_amperes", "tasmota_power_watts", "tasmota_apparent_power_voltamperes",
 		"tasmota_reactive_power_voltamperesreactive", "tasmota_power_factor", "tasmota_today_kwh_total",
-		"tasmota_yesterday_kwh_total", "tasmota_kwh_total", "tasmota_temperature_celsius")
+		"tasmota_yesterday_kwh_total", "tasmota_energy_kwh_total", "tasmota_temperature_celsius")
 
 	if descCount != expectedDescs {
 		t.Errorf("expected %d metric descriptions, got %d", expectedDescs, descCount)
 	}
 
-	// Test Collect - should have 12 metrics (all our gauges for one outlet)
+	// Test Collect - should have 12 metrics (all our gauges plus the energy counter, for one outlet)
 	expectedMetrics := 12
 	metricCount := testutil.CollectAndCount(collector)
 	if metricCount != expectedMetrics {
@@ -80,14 +81,98 @@ func TestTasmotaCollector_Smoke(t *testing.T) {
 tasmota_up{outlet="test-outlet"} 1
 # HELP tasmota_power_watts current power of tasmota plug in watts (W)
 # TYPE tasmota_power_watts gauge
-tasmota_power_watts{outlet="test-outlet"} 45.2
+tasmota_power_watts{outlet="test-outlet",relay="1"} 45.2
 # HELP tasmota_temperature_celsius temperature of the ESP32 chip in celsius
 # TYPE tasmota_temperature_celsius gauge
 tasmota_temperature_celsius{outlet="test-outlet"} 42.5
+# HELP tasmota_energy_kwh_total cumulative energy usage in kilowatt hours (kWh), monotonically increasing across device-side counter resets
+# TYPE tasmota_energy_kwh_total counter
+tasmota_energy_kwh_total{outlet="test-outlet",relay="1"} 1.5
 `
 
 	if err := testutil.CollectAndCompare(collector, strings.NewReader(expectedMetricsText),
-		"tasmota_up", "tasmota_power_watts", "tasmota_temperature_celsius"); err != nil {
+		"tasmota_up", "tasmota_power_watts", "tasmota_temperature_celsius", "tasmota_energy_kwh_total"); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestTasmotaCollector_EnergyCounterRebaselinesOnDeviceReset(t *testing.T) {
+	responses := []TasmotaStatus{
+		{StatusSNS: StatusSNS{ENERGY: Energy{TotalStartTime: "2025-01-15T00:00:00", Total: flexFloats{9.0}}}},
+		{StatusSNS: StatusSNS{ENERGY: Energy{TotalStartTime: "2025-01-16T00:00:00", Total: flexFloats{1.0}}}},
+	}
+	call := 0
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(responses[call]); err != nil {
+			t.Fatalf("failed to encode response: %v", err)
+		}
+		call++
+	}))
+	defer mockServer.Close()
+
+	outlets := []Outlet{
+		{Name: "test-outlet", IP: mockServer.Listener.Addr().String()},
+	}
+	collector := NewTasmotaCollector(outlets)
+
+	testutil.CollectAndCount(collector) // first scrape: Total=9.0, no reset seen yet
+
+	expectedMetricsText := `# HELP tasmota_energy_kwh_total cumulative energy usage in kilowatt hours (kWh), monotonically increasing across device-side counter resets
+# TYPE tasmota_energy_kwh_total counter
+tasmota_energy_kwh_total{outlet="test-outlet",relay="1"} 10
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expectedMetricsText), "tasmota_energy_kwh_total"); err != nil {
+		t.Errorf("unexpected collecting result after device reset:\n%s", err)
+	}
+}
+
+func TestTasmotaCollector_MultiRelay(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := TasmotaStatus{
+			StatusSNS: StatusSNS{
+				ENERGY: Energy{
+					TotalStartTime: "2025-01-15T00:00:00",
+					Total:          flexFloats{5.0, 2.0},
+					Power:          flexFloats{10.5, 0},
+					Voltage:        flexFloats{230.0},
+				},
+			},
+			StatusSTS: StatusSTS{POWER1: "ON", POWER2: "OFF"},
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Errorf("failed to encode response: %v", err)
+		}
+	}))
+	defer mockServer.Close()
+
+	outlets := []Outlet{
+		{Name: "4ch", IP: mockServer.Listener.Addr().String()},
+	}
+	collector := NewTasmotaCollector(outlets)
+
+	expectedMetricsText := `# HELP tasmota_on Indicates if the tasmota plug is on/off
+# TYPE tasmota_on gauge
+tasmota_on{outlet="4ch",relay="1"} 1
+tasmota_on{outlet="4ch",relay="2"} 0
+# HELP tasmota_power_watts current power of tasmota plug in watts (W)
+# TYPE tasmota_power_watts gauge
+tasmota_power_watts{outlet="4ch",relay="1"} 10.5
+tasmota_power_watts{outlet="4ch",relay="2"} 0
+# HELP tasmota_voltage_volts voltage of tasmota plug in volt (V)
+# TYPE tasmota_voltage_volts gauge
+tasmota_voltage_volts{outlet="4ch",relay="1"} 230
+tasmota_voltage_volts{outlet="4ch",relay="2"} 230
+# HELP tasmota_energy_kwh_total cumulative energy usage in kilowatt hours (kWh), monotonically increasing across device-side counter resets
+# TYPE tasmota_energy_kwh_total counter
+tasmota_energy_kwh_total{outlet="4ch",relay="1"} 5
+tasmota_energy_kwh_total{outlet="4ch",relay="2"} 2
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expectedMetricsText),
+		"tasmota_on", "tasmota_power_watts", "tasmota_voltage_volts", "tasmota_energy_kwh_total"); err != nil {
 		t.Errorf("unexpected collecting result:\n%s", err)
 	}
 }
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// MQTTConfig holds the settings needed to connect to the broker and subscribe
+// to the outlets' telemetry topics.
+type MQTTConfig struct {
+	Broker      string
+	TopicPrefix string
+	Username    string
+	Password    string
+}
+
+// MQTTIngester subscribes to Tasmota's tele/<topic>/SENSOR and
+// stat/<topic>/RESULT messages and feeds the decoded payloads into a
+// TasmotaCollector, as an alternative to polling probeTasmota over HTTP.
+// This suits battery-powered or firewalled devices that push telemetry on
+// their own teleperiod rather than accepting inbound requests. RESULT
+// carries the relay state a command produced, so without it an MQTT-fed
+// outlet's on/off state only updates on the next teleperiod, and
+// multi-relay devices never report StatusSTS at all.
+type MQTTIngester struct {
+	client    mqtt.Client
+	collector *collectorRef
+}
+
+// NewMQTTIngester connects to the configured broker and subscribes to the
+// SENSOR and RESULT topics for every outlet, routing messages to the
+// collector currently held by collector.
+func NewMQTTIngester(cfg MQTTConfig, collector *collectorRef) (*MQTTIngester, error) {
+	ing := &MQTTIngester{collector: collector}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID("tasmota-exporter").
+		SetOnConnectHandler(ing.onConnect(cfg.TopicPrefix))
+	if cfg.Username != "" {
+		opts.SetUsername(cfg.Username)
+		opts.SetPassword(cfg.Password)
+	}
+
+	client := mqtt.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		return nil, fmt.Errorf("failed to connect to mqtt broker %s: %w", cfg.Broker, token.Error())
+	}
+	ing.client = client
+
+	return ing, nil
+}
+
+// onConnect returns an OnConnect handler that subscribes to the SENSOR and
+// RESULT topics for every outlet, so subscriptions are also re-established
+// after a reconnect.
+func (i *MQTTIngester) onConnect(topicPrefix string) mqtt.OnConnectHandler {
+	return func(client mqtt.Client) {
+		sensorTopic := subTopic(topicPrefix, "tele/+/SENSOR")
+		if token := client.Subscribe(sensorTopic, 0, i.handleSensor); token.Wait() && token.Error() != nil {
+			slog.Error("failed to subscribe to mqtt topic", "topic", sensorTopic, "error", token.Error())
+		}
+
+		resultTopic := subTopic(topicPrefix, "stat/+/RESULT")
+		if token := client.Subscribe(resultTopic, 0, i.handleResult); token.Wait() && token.Error() != nil {
+			slog.Error("failed to subscribe to mqtt topic", "topic", resultTopic, "error", token.Error())
+		}
+	}
+}
+
+// subTopic prefixes suffix with topicPrefix, trimming the slashes that
+// otherwise end up doubled or leading.
+func subTopic(topicPrefix, suffix string) string {
+	topic := strings.TrimSuffix(topicPrefix, "/") + "/" + suffix
+	return strings.TrimPrefix(topic, "/")
+}
+
+// deviceTopicFrom extracts the device topic segment from a tele/<topic>/SENSOR
+// or stat/<topic>/RESULT message topic.
+func deviceTopicFrom(msgTopic string) (string, bool) {
+	parts := strings.Split(msgTopic, "/")
+	if len(parts) < 3 {
+		return "", false
+	}
+	return parts[len(parts)-2], true
+}
+
+// handleSensor decodes a tele/<topic>/SENSOR payload and records it against
+// the outlet whose Topic matches the message's topic segment.
+func (i *MQTTIngester) handleSensor(_ mqtt.Client, msg mqtt.Message) {
+	deviceTopic, ok := deviceTopicFrom(msg.Topic())
+	if !ok {
+		slog.Warn("unexpected mqtt topic", "topic", msg.Topic())
+		return
+	}
+
+	var sns StatusSNS
+	if err := json.Unmarshal(msg.Payload(), &sns); err != nil {
+		slog.Warn("failed to parse mqtt sensor payload", "topic", msg.Topic(), "error", err)
+		return
+	}
+
+	i.collector.Get().updateFromMQTT(deviceTopic, &sns, nil)
+}
+
+// handleResult decodes a stat/<topic>/RESULT payload — published whenever a
+// command changes a relay's state — and records it against the outlet whose
+// Topic matches the message's topic segment.
+func (i *MQTTIngester) handleResult(_ mqtt.Client, msg mqtt.Message) {
+	deviceTopic, ok := deviceTopicFrom(msg.Topic())
+	if !ok {
+		slog.Warn("unexpected mqtt topic", "topic", msg.Topic())
+		return
+	}
+
+	var sts StatusSTS
+	if err := json.Unmarshal(msg.Payload(), &sts); err != nil {
+		slog.Warn("failed to parse mqtt result payload", "topic", msg.Topic(), "error", err)
+		return
+	}
+
+	i.collector.Get().updateFromMQTT(deviceTopic, nil, &sts)
+}
+
+// Close disconnects the underlying MQTT client.
+func (i *MQTTIngester) Close() {
+	i.client.Disconnect(250)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeMQTTMessage is a minimal mqtt.Message for feeding payloads to
+// MQTTIngester's handlers directly, without a real broker.
+type fakeMQTTMessage struct {
+	topic   string
+	payload []byte
+}
+
+func (m *fakeMQTTMessage) Duplicate() bool   { return false }
+func (m *fakeMQTTMessage) Qos() byte         { return 0 }
+func (m *fakeMQTTMessage) Retained() bool    { return false }
+func (m *fakeMQTTMessage) Topic() string     { return m.topic }
+func (m *fakeMQTTMessage) MessageID() uint16 { return 0 }
+func (m *fakeMQTTMessage) Payload() []byte   { return m.payload }
+func (m *fakeMQTTMessage) Ack()              {}
+
+func TestSubTopic(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		suffix string
+		want   string
+	}{
+		{name: "no prefix", prefix: "", suffix: "tele/+/SENSOR", want: "tele/+/SENSOR"},
+		{name: "prefix without trailing slash", prefix: "home", suffix: "tele/+/SENSOR", want: "home/tele/+/SENSOR"},
+		{name: "prefix with trailing slash", prefix: "home/", suffix: "tele/+/SENSOR", want: "home/tele/+/SENSOR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := subTopic(tt.prefix, tt.suffix); got != tt.want {
+				t.Errorf("subTopic(%q, %q) = %q, want %q", tt.prefix, tt.suffix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeviceTopicFrom(t *testing.T) {
+	tests := []struct {
+		name     string
+		topic    string
+		wantName string
+		wantOK   bool
+	}{
+		{name: "sensor topic", topic: "tele/bedroom/SENSOR", wantName: "bedroom", wantOK: true},
+		{name: "result topic", topic: "stat/bedroom/RESULT", wantName: "bedroom", wantOK: true},
+		{name: "prefixed topic", topic: "home/tele/bedroom/SENSOR", wantName: "bedroom", wantOK: true},
+		{name: "too short", topic: "SENSOR", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := deviceTopicFrom(tt.topic)
+			if ok != tt.wantOK || got != tt.wantName {
+				t.Errorf("deviceTopicFrom(%q) = (%q, %v), want (%q, %v)", tt.topic, got, ok, tt.wantName, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMQTTIngester_HandleSensor_FallsBackToPowerHeuristic(t *testing.T) {
+	ref := &collectorRef{}
+	ref.Set(NewTasmotaCollector([]Outlet{{Name: "bedroom", Topic: "bedroom-plug"}}))
+	ing := &MQTTIngester{collector: ref}
+
+	// A SENSOR-only device (no command ever issued, so no RESULT has ever
+	// arrived) reporting live power draw should still report on=1, not fall
+	// back to a never-received StatusSTS's zero value.
+	ing.handleSensor(nil, &fakeMQTTMessage{
+		topic:   "tele/bedroom-plug/SENSOR",
+		payload: []byte(`{"ENERGY":{"Power":42.0}}`),
+	})
+
+	expected := `# HELP tasmota_on Indicates if the tasmota plug is on/off
+# TYPE tasmota_on gauge
+tasmota_on{outlet="bedroom",relay="1"} 1
+`
+	if err := testutil.CollectAndCompare(ref.Get(), strings.NewReader(expected), "tasmota_on"); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestMQTTIngester_HandleResult_OverridesPowerHeuristic(t *testing.T) {
+	ref := &collectorRef{}
+	ref.Set(NewTasmotaCollector([]Outlet{{Name: "bedroom", Topic: "bedroom-plug"}}))
+	ing := &MQTTIngester{collector: ref}
+
+	ing.handleSensor(nil, &fakeMQTTMessage{
+		topic:   "tele/bedroom-plug/SENSOR",
+		payload: []byte(`{"ENERGY":{"Power":42.0}}`),
+	})
+	// A RESULT saying the relay is actually off should be trusted even
+	// though power draw (momentarily, or due to measurement lag) looks on.
+	ing.handleResult(nil, &fakeMQTTMessage{
+		topic:   "stat/bedroom-plug/RESULT",
+		payload: []byte(`{"POWER":"OFF"}`),
+	})
+
+	expected := `# HELP tasmota_on Indicates if the tasmota plug is on/off
+# TYPE tasmota_on gauge
+tasmota_on{outlet="bedroom",relay="1"} 0
+`
+	if err := testutil.CollectAndCompare(ref.Get(), strings.NewReader(expected), "tasmota_on"); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestCollectFromMQTT_Staleness(t *testing.T) {
+	collector := NewTasmotaCollector([]Outlet{{Name: "bedroom", Topic: "bedroom-plug"}})
+	collector.SetStaleAfter(10 * time.Millisecond)
+
+	if n := testutil.CollectAndCount(collector, "tasmota_up"); n != 1 {
+		t.Fatalf("expected 1 tasmota_up metric for a never-seen outlet, got %d", n)
+	}
+	expected := `# HELP tasmota_up Indicates if the tasmota outlet is reachable
+# TYPE tasmota_up gauge
+tasmota_up{outlet="bedroom"} 0
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "tasmota_up"); err != nil {
+		t.Errorf("unexpected collecting result for a never-seen outlet:\n%s", err)
+	}
+
+	collector.updateFromMQTT("bedroom-plug", &StatusSNS{ENERGY: Energy{Power: flexFloats{1}}}, nil)
+
+	expected = `# HELP tasmota_up Indicates if the tasmota outlet is reachable
+# TYPE tasmota_up gauge
+tasmota_up{outlet="bedroom"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "tasmota_up"); err != nil {
+		t.Errorf("unexpected collecting result right after a sample:\n%s", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	expected = `# HELP tasmota_up Indicates if the tasmota outlet is reachable
+# TYPE tasmota_up gauge
+tasmota_up{outlet="bedroom"} 0
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "tasmota_up"); err != nil {
+		t.Errorf("unexpected collecting result once the sample has gone stale:\n%s", err)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// probeMetrics holds the unlabeled gauges populated for a single /probe
+// request. Unlike TasmotaCollector's gauges, these carry no "outlet" label:
+// the target is already implied by the one-shot registry they live on.
+type probeMetrics struct {
+	on            prometheus.Gauge
+	voltage       prometheus.Gauge
+	current       prometheus.Gauge
+	power         prometheus.Gauge
+	apparentPower prometheus.Gauge
+	reactivePower prometheus.Gauge
+	factor        prometheus.Gauge
+	today         prometheus.Gauge
+	yesterday     prometheus.Gauge
+	// total is tasmota_energy_kwh_total, reported as a counter rather than a
+	// gauge so it matches TasmotaCollector's metric of the same name on
+	// /metrics; see energyTotalCounter.
+	total       *energyTotalCounter
+	temperature prometheus.Gauge
+}
+
+// energyTotalCounter implements prometheus.Collector to emit a single
+// tasmota_energy_kwh_total counter sample. /probe has no prior reading to
+// re-baseline a device-side counter reset against (unlike
+// TasmotaCollector.energyTotal), so it reports the device's raw cumulative
+// total as-is.
+type energyTotalCounter struct {
+	desc  *prometheus.Desc
+	value float64
+}
+
+func newEnergyTotalCounter() *energyTotalCounter {
+	return &energyTotalCounter{
+		desc: prometheus.NewDesc(
+			"tasmota_energy_kwh_total",
+			"cumulative energy usage in kilowatt hours (kWh), as reported by the device",
+			nil, nil,
+		),
+	}
+}
+
+func (e *energyTotalCounter) Describe(ch chan<- *prometheus.Desc) { ch <- e.desc }
+
+func (e *energyTotalCounter) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(e.desc, prometheus.CounterValue, e.value)
+}
+
+func newProbeMetrics() *probeMetrics {
+	return &probeMetrics{
+		on: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tasmota_on",
+			Help: "Indicates if the tasmota plug is on/off",
+		}),
+		voltage: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tasmota_voltage_volts",
+			Help: "voltage of tasmota plug in volt (V)",
+		}),
+		current: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tasmota_current_amperes",
+			Help: "current of tasmota plug in ampere (A)",
+		}),
+		power: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tasmota_power_watts",
+			Help: "current power of tasmota plug in watts (W)",
+		}),
+		apparentPower: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tasmota_apparent_power_voltamperes",
+			Help: "apparent power of tasmota plug in volt-amperes (VA)",
+		}),
+		reactivePower: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tasmota_reactive_power_voltamperesreactive",
+			Help: "reactive power of tasmota plug in volt-amperes reactive (VAr)",
+		}),
+		factor: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tasmota_power_factor",
+			Help: "power factor of tasmota plug",
+		}),
+		today: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tasmota_today_kwh_total",
+			Help: "todays energy usage total in kilowatts hours (kWh)",
+		}),
+		yesterday: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tasmota_yesterday_kwh_total",
+			Help: "yesterdays energy usage total in kilowatts hours (kWh)",
+		}),
+		total: newEnergyTotalCounter(),
+		temperature: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tasmota_temperature_celsius",
+			Help: "temperature of the ESP32 chip in celsius",
+		}),
+	}
+}
+
+func (m *probeMetrics) register(reg *prometheus.Registry) {
+	reg.MustRegister(m.on, m.voltage, m.current, m.power, m.apparentPower,
+		m.reactivePower, m.factor, m.today, m.yesterday, m.total, m.temperature)
+}
+
+// set populates the probe's gauges from status's first relay. /probe targets
+// an ad-hoc outlet by IP rather than a configured one, so it has no way to
+// select a relay; multi-relay devices should be added via -outlets or
+// -config.file instead, where each relay gets its own labeled series.
+func (m *probeMetrics) set(status *TasmotaStatus) {
+	energy := status.StatusSNS.ENERGY
+	on := energy.Power.at(0) > 0
+	if relays := status.StatusSTS.Relays(); len(relays) > 0 {
+		on = relays[0]
+	}
+	if on {
+		m.on.Set(1)
+	} else {
+		m.on.Set(0)
+	}
+	m.voltage.Set(energy.Voltage.at(0))
+	m.current.Set(energy.Current.at(0))
+	m.power.Set(energy.Power.at(0))
+	m.apparentPower.Set(energy.ApparentPower.at(0))
+	m.reactivePower.Set(energy.ReactivePower.at(0))
+	m.factor.Set(energy.Factor.at(0))
+	m.today.Set(energy.Today.at(0))
+	m.yesterday.Set(energy.Yesterday.at(0))
+	m.total.value = energy.Total.at(0)
+	m.temperature.Set(status.StatusSNS.ESP32.Temperature)
+}
+
+// probeHandler implements a blackbox_exporter-style /probe endpoint: it
+// scrapes a single Tasmota device named by the target query parameter and
+// serves its metrics on a fresh, one-shot registry, alongside
+// tasmota_probe_success and tasmota_probe_duration_seconds describing the
+// probe itself. This lets Prometheus service-discover Tasmota devices
+// instead of requiring every one to be listed in the static -outlets flag.
+func probeHandler(ref *collectorRef) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := ref.Get()
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			name = target
+		}
+
+		// module is accepted but unused: the probe always queries the target
+		// over plain HTTP, without credentials, with a 5s timeout.
+		_ = r.URL.Query().Get("module")
+
+		registry := prometheus.NewRegistry()
+
+		probeSuccessGauge := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tasmota_probe_success",
+			Help: "Displays whether or not the probe was a success",
+		})
+		probeDurationGauge := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tasmota_probe_duration_seconds",
+			Help: "Returns how long the probe took to complete in seconds",
+		})
+		registry.MustRegister(probeSuccessGauge, probeDurationGauge)
+
+		metrics := newProbeMetrics()
+		metrics.register(registry)
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		status, err := c.probeTasmota(ctx, Outlet{Name: name, IP: target})
+		probeDurationGauge.Set(time.Since(start).Seconds())
+
+		if err != nil {
+			slog.Warn("probe failed", "name", name, "target", target, "error", err)
+			probeSuccessGauge.Set(0)
+		} else {
+			slog.Info("probe successful", "name", name, "target", target)
+			probeSuccessGauge.Set(1)
+			metrics.set(status)
+		}
+
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}
+}
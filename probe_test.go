@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProbeHandler_MissingTarget(t *testing.T) {
+	ref := &collectorRef{}
+	ref.Set(NewTasmotaCollector(nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/probe", nil)
+	w := httptest.NewRecorder()
+
+	probeHandler(ref)(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestProbeHandler_Success(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := TasmotaStatus{
+			StatusSNS: StatusSNS{
+				ENERGY: Energy{
+					Total: flexFloats{3.5},
+					Power: flexFloats{12.0},
+				},
+			},
+			StatusSTS: StatusSTS{POWER: "ON"},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Errorf("failed to encode response: %v", err)
+		}
+	}))
+	defer mockServer.Close()
+
+	ref := &collectorRef{}
+	ref.Set(NewTasmotaCollector(nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/probe?target="+mockServer.Listener.Addr().String(), nil)
+	w := httptest.NewRecorder()
+
+	probeHandler(ref)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		"tasmota_probe_success 1",
+		"# TYPE tasmota_energy_kwh_total counter",
+		"tasmota_energy_kwh_total 3.5",
+		"tasmota_power_watts 12",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response missing %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestProbeHandler_Failure(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}))
+	defer mockServer.Close()
+
+	ref := &collectorRef{}
+	ref.Set(NewTasmotaCollector(nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/probe?target="+mockServer.Listener.Addr().String(), nil)
+	w := httptest.NewRecorder()
+
+	probeHandler(ref)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, "tasmota_probe_success 0") {
+		t.Errorf("response missing probe failure indicator, got:\n%s", body)
+	}
+	// The probe's device gauges are registered up front and report their
+	// zero value on a failed probe, since metrics.set is only called on
+	// success.
+	if !strings.Contains(body, "tasmota_power_watts 0") {
+		t.Errorf("response missing zero-valued device metrics on probe failure, got:\n%s", body)
+	}
+}
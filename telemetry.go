@@ -0,0 +1,70 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// dataRegistry holds only the outlet gauges (tasmota_on, tasmota_power_watts,
+// etc). It is served on /metrics and deliberately excludes Go/process stats
+// and scrape telemetry, which live on telemetryRegistry instead, so the two
+// can be alerted on and scraped independently.
+var dataRegistry = prometheus.NewRegistry()
+
+// telemetryRegistry exposes the exporter's own health: Go/process stats plus
+// per-outlet scrape duration and request counts, so chronic scrape failures
+// or slow devices can be diagnosed without mixing them into the data a user
+// is graphing. It is served on /telemetry.
+var telemetryRegistry = prometheus.NewRegistry()
+
+var (
+	scrapeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "tasmota_scrape_duration_seconds",
+		Help: "Time spent probing a tasmota outlet over HTTP",
+	}, []string{"outlet"})
+
+	scrapeRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tasmota_scrape_requests_total",
+		Help: "Total number of scrape requests made to a tasmota outlet, by result",
+	}, []string{"outlet", "status"})
+
+	powerCommands = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tasmota_power_command_total",
+		Help: "Total number of outlet power commands issued via the control API, by outlet, command and result",
+	}, []string{"outlet", "command", "result"})
+)
+
+func init() {
+	telemetryRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		scrapeDuration,
+		scrapeRequests,
+		powerCommands,
+	)
+}
+
+// observeScrape records the outcome of a single probeTasmota call against
+// the telemetry registry's per-outlet duration histogram and request
+// counter.
+func observeScrape(outlet string, start time.Time, err error) {
+	scrapeDuration.WithLabelValues(outlet).Observe(time.Since(start).Seconds())
+
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	scrapeRequests.WithLabelValues(outlet, status).Inc()
+}
+
+// observePowerCommand records the outcome of a control API power command
+// against the telemetry registry's command counter.
+func observePowerCommand(outlet, command string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	powerCommands.WithLabelValues(outlet, command, result).Inc()
+}